@@ -0,0 +1,166 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatusPrecedence(t *testing.T) {
+	if statusPrecedence("FAIL") <= statusPrecedence("PASS") {
+		t.Error("FAIL should outrank PASS")
+	}
+	if statusPrecedence("PASS") <= statusPrecedence("SKIP") {
+		t.Error("PASS should outrank SKIP")
+	}
+	if statusPrecedence("SKIP") <= statusPrecedence("unknown") {
+		t.Error("SKIP should outrank an unrecognized status")
+	}
+}
+
+func TestMergeReportsDeduplicatesByPrecedence(t *testing.T) {
+	reportA := TestNGReport{
+		Suites: []Suite{
+			{
+				Name: "suite1",
+				Classes: []Class{
+					{
+						Name: "com.example.Tests",
+						Tests: []Test{
+							{Name: "testFoo", Status: "PASS"},
+							{Name: "testBar", Status: "FAIL"},
+						},
+					},
+				},
+			},
+		},
+	}
+	reportB := TestNGReport{
+		Suites: []Suite{
+			{
+				Name: "suite1",
+				Classes: []Class{
+					{
+						Name: "com.example.Tests",
+						Tests: []Test{
+							// Reruns: a later FAIL must override the earlier PASS, and a
+							// later PASS must not override the earlier FAIL.
+							{Name: "testFoo", Status: "FAIL"},
+							{Name: "testBar", Status: "PASS"},
+							{Name: "testBaz", Status: "SKIP"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	merged := mergeReports([]TestNGReport{reportA, reportB})
+
+	if len(merged.Suites) != 1 {
+		t.Fatalf("expected 1 merged suite, got %d", len(merged.Suites))
+	}
+	suite := merged.Suites[0]
+	if len(suite.Classes) != 1 || len(suite.Classes[0].Tests) != 3 {
+		t.Fatalf("expected 3 deduplicated tests, got %+v", suite.Classes)
+	}
+
+	statusByName := make(map[string]string)
+	for _, test := range suite.Classes[0].Tests {
+		statusByName[test.Name] = test.Status
+	}
+
+	if statusByName["testFoo"] != "FAIL" {
+		t.Errorf("testFoo = %q, want FAIL (FAIL overrides PASS)", statusByName["testFoo"])
+	}
+	if statusByName["testBar"] != "FAIL" {
+		t.Errorf("testBar = %q, want FAIL (PASS must not override FAIL)", statusByName["testBar"])
+	}
+	if statusByName["testBaz"] != "SKIP" {
+		t.Errorf("testBaz = %q, want SKIP", statusByName["testBaz"])
+	}
+
+	if suite.Tests != 3 || suite.Failures != 2 || suite.Skipped != 1 {
+		t.Errorf("recalculated totals = tests=%d failures=%d skipped=%d, want 3/2/1",
+			suite.Tests, suite.Failures, suite.Skipped)
+	}
+}
+
+func TestRecalculateSuiteTotalsIgnoresConfigMethods(t *testing.T) {
+	suite := &Suite{
+		Classes: []Class{
+			{
+				Name: "com.example.Tests",
+				Tests: []Test{
+					{Name: "beforeMethod", IsConfig: true, Status: "FAIL"},
+					{Name: "testFoo", Status: "PASS"},
+					{Name: "testBar", Status: "FAIL"},
+				},
+			},
+		},
+	}
+
+	recalculateSuiteTotals(suite)
+
+	if suite.Tests != 2 {
+		t.Errorf("Tests = %d, want 2 (config method excluded)", suite.Tests)
+	}
+	if suite.Failures != 1 {
+		t.Errorf("Failures = %d, want 1 (config method's FAIL excluded)", suite.Failures)
+	}
+}
+
+func TestValidateConfigFailures(t *testing.T) {
+	suite := Suite{
+		Classes: []Class{
+			{
+				Name: "com.example.Tests",
+				Tests: []Test{
+					{Name: "beforeMethod", IsConfig: true, Status: "FAIL"},
+				},
+			},
+		},
+	}
+
+	if err := validateConfigFailures(suite, Args{FailureOnFailedTestConfig: true}); err == nil {
+		t.Error("expected an error for a failed configuration method")
+	}
+	if err := validateConfigFailures(suite, Args{FailureOnFailedTestConfig: false}); err != nil {
+		t.Errorf("expected no error when FailureOnFailedTestConfig is disabled, got %v", err)
+	}
+}
+
+// TestMergeReportsToValidatesAggregateThresholds exercises a sharded/parallel TestNG
+// run: two shard files each individually satisfy "failures <= 1", but their merged,
+// deduplicated total of 2 failures does not. Thresholds must be validated against the
+// merged report, not per source file, or this build would wrongly pass.
+func TestMergeReportsToValidatesAggregateThresholds(t *testing.T) {
+	shardA := TestNGReport{
+		Suites: []Suite{
+			{
+				Name: "suite1", Tests: 1, Failures: 1,
+				Classes: []Class{{Name: "com.example.Tests", Tests: []Test{{Name: "testA", Status: "FAIL"}}}},
+			},
+		},
+	}
+	shardB := TestNGReport{
+		Suites: []Suite{
+			{
+				Name: "suite1", Tests: 1, Failures: 1,
+				Classes: []Class{{Name: "com.example.Tests", Tests: []Test{{Name: "testB", Status: "FAIL"}}}},
+			},
+		},
+	}
+
+	args := Args{Thresholds: []string{"failures <= 1"}}
+
+	outputPath := filepath.Join(t.TempDir(), "merged.xml")
+	err := mergeReportsTo(outputPath, []TestNGReport{shardA, shardB}, args)
+	if err == nil {
+		t.Fatal("expected the merged aggregate's 2 failures to violate \"failures <= 1\", got nil error")
+	}
+
+	if _, statErr := os.Stat(outputPath); statErr == nil {
+		t.Error("merged output should not be written when its thresholds fail")
+	}
+}
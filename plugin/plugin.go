@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -19,11 +20,44 @@ type Args struct {
 	FailureOnFailedTestConfig bool   `envconfig:"PLUGIN_FAILURE_ON_FAILED_TEST_CONFIG"`
 	UnstableFails             int    `envconfig:"PLUGIN_UNSTABLE_FAILS"`
 	UnstableSkips             int    `envconfig:"PLUGIN_UNSTABLE_SKIPS"`
-	ThresholdMode             int    `envconfig:"PLUGIN_THRESHOLD_MODE"`
-	PluginFailIfNoResults     bool   `envconfig:"PLUGIN_FAIL_IF_NO_RESULTS"`
-	Level                     string `envconfig:"PLUGIN_LOG_LEVEL"`
+	// ThresholdMode is deprecated in favor of Thresholds; 1 (absolute) or 2 (percentage)
+	// is translated into the equivalent expression form by legacyThresholds.
+	ThresholdMode         int    `envconfig:"PLUGIN_THRESHOLD_MODE"`
+	PluginFailIfNoResults bool   `envconfig:"PLUGIN_FAIL_IF_NO_RESULTS"`
+	Level                 string `envconfig:"PLUGIN_LOG_LEVEL"`
+	// Thresholds are boolean expressions over a suite's metrics (tests, failures,
+	// skipped, passed, duration_ms, failure_rate, skip_rate) that must all evaluate to
+	// true, e.g. "failures < 5" or "failures/tests < 0.1". Any expression that
+	// evaluates to false fails the build.
+	Thresholds []string `envconfig:"PLUGIN_THRESHOLDS"`
+	// UnstableThresholds are evaluated the same way as Thresholds but only log a
+	// warning instead of failing the build.
+	UnstableThresholds []string `envconfig:"PLUGIN_UNSTABLE_THRESHOLDS"`
+	// MergeOutputPath, when set, collapses every matched TestNG report into a single
+	// deduplicated JUnit document written to this path, in addition to any individual
+	// per-file conversions.
+	MergeOutputPath string `envconfig:"PLUGIN_MERGE_OUTPUT_PATH"`
+	// IncludeEnvProperties adds the plugin process's environment variables to each
+	// JUnit suite's <properties> block, alongside any TestNG test parameters.
+	IncludeEnvProperties bool `envconfig:"PLUGIN_INCLUDE_ENV_PROPERTIES"`
+	// JUnitOutFile writes the converted JUnit XML to this exact path, leaving the
+	// original TestNG report untouched. Only valid when exactly one file matches
+	// ReportFilenamePattern; use JUnitOutDir for the multi-file case.
+	JUnitOutFile string `envconfig:"PLUGIN_JUNIT_OUT_FILE"`
+	// JUnitOutDir writes each converted file's JUnit XML under this directory, using
+	// the original file's base name, leaving the original TestNG reports untouched.
+	JUnitOutDir string `envconfig:"PLUGIN_JUNIT_OUT_DIR"`
+	// InPlace rewrites each matched TestNG report with its JUnit conversion. It only
+	// takes effect when neither JUnitOutFile nor JUnitOutDir is set; if none of the
+	// three are set, the converted output is discarded.
+	InPlace bool `envconfig:"PLUGIN_IN_PLACE"`
 }
 
+// assertionErrorType is the TestNG exception class for a failed assertion. Any other
+// exception type reported against a FAIL testcase is treated as a JUnit <error> rather
+// than a <failure>.
+const assertionErrorType = "java.lang.AssertionError"
+
 // ValidateInputs ensures the user inputs meet the plugin requirements.
 func ValidateInputs(args Args) error {
 	if args.ReportFilenamePattern == "" {
@@ -32,9 +66,17 @@ func ValidateInputs(args Args) error {
 	if args.FailedFails < 0 || args.FailedSkips < 0 || args.UnstableFails < 0 || args.UnstableSkips < 0 {
 		return errors.New("threshold values must be non-negative")
 	}
-	if args.ThresholdMode != 1 && args.ThresholdMode != 2 {
+	if args.ThresholdMode != 0 && args.ThresholdMode != 1 && args.ThresholdMode != 2 {
 		return errors.New("thresholdMode must be 1 (absolute) or 2 (percentage)")
 	}
+	for _, expr := range append(append([]string{}, args.Thresholds...), args.UnstableThresholds...) {
+		if _, err := parseExpression(expr); err != nil {
+			return errors.New("invalid threshold expression " + strconv.Quote(expr) + ": " + err.Error())
+		}
+	}
+	if args.JUnitOutFile != "" && args.JUnitOutDir != "" {
+		return errors.New("only one of JUnitOutFile or JUnitOutDir may be set")
+	}
 	return nil
 }
 
@@ -49,8 +91,41 @@ func Exec(ctx context.Context, args Args) error {
 		return errors.New("no TestNG XML report files found")
 	}
 
+	multipleFiles := len(files) > 1
+
+	// Merging requires every suite to be held in memory at once for cross-file
+	// deduplication, so that path still does a full parse. Otherwise each file is
+	// streamed through convertFile, bounding peak memory to a single suite.
+	if args.MergeOutputPath != "" {
+		// Thresholds are validated once against the merged, deduplicated report in
+		// mergeReportsTo below, not per source file: a sharded/parallel TestNG run's
+		// individual files are expected to be partial, so checking them independently
+		// would validate numbers that don't reflect the actual aggregate result.
+		reports := make([]TestNGReport, 0, len(files))
+		for _, file := range files {
+			report, err := parseTestNGFile(file)
+			if err != nil {
+				return err
+			}
+
+			outputPath, discard, err := resolveIndividualOutput(file, args, multipleFiles)
+			if err != nil {
+				return err
+			}
+			if !discard {
+				if err := processFile(file, outputPath, report, args); err != nil {
+					return err
+				}
+			}
+
+			reports = append(reports, report)
+		}
+
+		return mergeReportsTo(args.MergeOutputPath, reports, args)
+	}
+
 	for _, file := range files {
-		if err := processFile(file, args); err != nil {
+		if err := convertFile(file, args, multipleFiles); err != nil {
 			return err
 		}
 	}
@@ -58,6 +133,25 @@ func Exec(ctx context.Context, args Args) error {
 	return nil
 }
 
+// resolveIndividualOutput decides where, if anywhere, a single matched file's JUnit
+// conversion should be written: JUnitOutFile (only valid for a single matched file),
+// a same-named file under JUnitOutDir, an in-place rewrite, or nowhere at all.
+func resolveIndividualOutput(filename string, args Args, multipleFiles bool) (outputPath string, discard bool, err error) {
+	switch {
+	case args.JUnitOutFile != "":
+		if multipleFiles {
+			return "", false, errors.New("JUnitOutFile cannot be used when multiple TestNG report files match; use JUnitOutDir instead")
+		}
+		return args.JUnitOutFile, false, nil
+	case args.JUnitOutDir != "":
+		return filepath.Join(args.JUnitOutDir, filepath.Base(filename)), false, nil
+	case args.InPlace:
+		return filename, false, nil
+	default:
+		return "", true, nil
+	}
+}
+
 // locateFiles identifies files matching the given pattern.
 func locateFiles(pattern string) ([]string, error) {
 	matches, err := filepath.Glob(pattern)
@@ -67,25 +161,27 @@ func locateFiles(pattern string) ([]string, error) {
 	return matches, nil
 }
 
-// processFile reads a TestNG XML, validates thresholds, converts it to JUnit XML, and writes it back.
-func processFile(filename string, args Args) error {
-	logrus.Infof("Processing file: %s", filename)
-
+// parseTestNGFile reads and unmarshals a single TestNG XML report.
+func parseTestNGFile(filename string) (TestNGReport, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return err
+		return TestNGReport{}, err
 	}
 
 	var testNGReport TestNGReport
 	if err := xml.Unmarshal(data, &testNGReport); err != nil {
-		return errors.New("failed to parse TestNG XML: " + err.Error())
+		return TestNGReport{}, errors.New("failed to parse TestNG XML: " + err.Error())
 	}
 
-	if err := validateThresholds(testNGReport, args); err != nil {
-		return err
-	}
+	return testNGReport, nil
+}
+
+// processFile converts an already-parsed TestNG report to JUnit XML and writes it to
+// outputPath, creating its parent directory if needed (for the JUnitOutDir case).
+func processFile(filename, outputPath string, testNGReport TestNGReport, args Args) error {
+	logrus.Infof("Processing file: %s", filename)
 
-	junitReport, err := convertToJUnit(testNGReport)
+	junitReport, err := convertToJUnit(testNGReport, args)
 	if err != nil {
 		return err
 	}
@@ -95,105 +191,154 @@ func processFile(filename string, args Args) error {
 		return errors.New("failed to marshal JUnit XML: " + err.Error())
 	}
 
-	if err := os.WriteFile(filename, outputData, 0644); err != nil {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
 		return err
 	}
 
-	logrus.Infof("Successfully converted %s to JUnit format", filename)
+	if err := os.WriteFile(outputPath, outputData, 0644); err != nil {
+		return err
+	}
+
+	logrus.Infof("Successfully converted %s to JUnit format at %s", filename, outputPath)
 	return nil
 }
 
-// validateThresholds checks thresholds like failed/skipped tests and configuration failures.
-func validateThresholds(report TestNGReport, args Args) error {
-	for _, suite := range report.Suites {
-		// Check thresholds based on the mode (absolute or percentage)
-		if args.ThresholdMode == 1 {
-			if err := validateAbsoluteThresholds(suite, args); err != nil {
-				return err
-			}
-		} else if args.ThresholdMode == 2 {
-			if err := validatePercentageThresholds(suite, args); err != nil {
-				return err
-			}
-		} else {
-			return errors.New("invalid thresholdMode: must be 1 (absolute) or 2 (percentage)")
-		}
+// mergeReportsTo collapses multiple TestNG reports into a single deduplicated JUnit
+// document and writes it to outputPath. Suites are merged by name, and within a suite,
+// testcases are deduplicated by (suite.Name, class.Name, test.Name): when the same
+// testcase appears more than once across files, a FAIL overrides a PASS, and both
+// override a SKIP.
+func mergeReportsTo(outputPath string, reports []TestNGReport, args Args) error {
+	merged := mergeReports(reports)
 
-		// Check for unstable thresholds
-		checkUnstableThresholds(suite, args)
+	if err := validateThresholds(merged, args); err != nil {
+		return err
+	}
 
-		// Check for configuration failures
-		if err := validateConfigFailures(suite, args); err != nil {
-			return err
-		}
+	junitReport, err := convertToJUnit(merged, args)
+	if err != nil {
+		return err
 	}
+
+	outputData, err := xml.MarshalIndent(junitReport, "", "  ")
+	if err != nil {
+		return errors.New("failed to marshal merged JUnit XML: " + err.Error())
+	}
+
+	if err := os.WriteFile(outputPath, outputData, 0644); err != nil {
+		return err
+	}
+
+	logrus.Infof("Successfully wrote merged JUnit report to %s", outputPath)
 	return nil
 }
 
-// validateAbsoluteThresholds checks absolute thresholds for failures and skips.
-func validateAbsoluteThresholds(suite Suite, args Args) error {
-	if args.FailedFails > 0 && suite.Failures > args.FailedFails {
-		return errors.New(
-			"number of failed tests exceeded the failure threshold: " +
-				"provided threshold=" + strconv.Itoa(args.FailedFails) +
-				", actual failed=" + strconv.Itoa(suite.Failures),
-		)
-	}
-	if args.FailedSkips > 0 && suite.Skipped > args.FailedSkips {
-		return errors.New(
-			"number of skipped tests exceeded the failure threshold: " +
-				"provided threshold=" + strconv.Itoa(args.FailedSkips) +
-				", actual skipped=" + strconv.Itoa(suite.Skipped),
-		)
+// mergeReports collapses the suites of multiple TestNG reports into one, applying
+// statusPrecedence to deduplicate testcases that appear in more than one file.
+func mergeReports(reports []TestNGReport) TestNGReport {
+	var order []string
+	suiteIndex := make(map[string]*Suite)
+
+	for _, report := range reports {
+		for _, suite := range report.Suites {
+			dst, ok := suiteIndex[suite.Name]
+			if !ok {
+				dst = &Suite{Name: suite.Name, Duration: suite.Duration}
+				suiteIndex[suite.Name] = dst
+				order = append(order, suite.Name)
+			}
+			mergeSuiteInto(dst, suite)
+		}
 	}
-	return nil
+
+	merged := TestNGReport{}
+	for _, name := range order {
+		suite := suiteIndex[name]
+		recalculateSuiteTotals(suite)
+		merged.Suites = append(merged.Suites, *suite)
+	}
+
+	return merged
 }
 
-// validatePercentageThresholds checks percentage-based thresholds for failures and skips.
-func validatePercentageThresholds(suite Suite, args Args) error {
-	totalTests := suite.Tests
-	if totalTests == 0 {
-		return nil // Avoid division by zero
+// mergeSuiteInto merges src's classes into dst, deduplicating testcases per class.
+func mergeSuiteInto(dst *Suite, src Suite) {
+	classIndex := make(map[string]int, len(dst.Classes))
+	for i, class := range dst.Classes {
+		classIndex[class.Name] = i
 	}
 
-	failureRate := float64(suite.Failures) / float64(totalTests) * 100
-	skipRate := float64(suite.Skipped) / float64(totalTests) * 100
+	for _, srcClass := range src.Classes {
+		i, ok := classIndex[srcClass.Name]
+		if !ok {
+			dst.Classes = append(dst.Classes, Class{Name: srcClass.Name})
+			i = len(dst.Classes) - 1
+			classIndex[srcClass.Name] = i
+		}
+		mergeClassInto(&dst.Classes[i], srcClass)
+	}
+}
 
-	if args.FailedFails > 0 && failureRate > float64(args.FailedFails) {
-		return errors.New(
-			"failure rate exceeded the failure threshold: " +
-				"provided threshold=" + strconv.Itoa(args.FailedFails) +
-				"%, actual failure rate=" + strconv.FormatFloat(failureRate, 'f', 2, 64) + "%",
-		)
+// mergeClassInto merges src's testcases into dst, keeping the higher-precedence
+// status when the same testcase name appears more than once.
+func mergeClassInto(dst *Class, src Class) {
+	testIndex := make(map[string]int, len(dst.Tests))
+	for i, test := range dst.Tests {
+		testIndex[test.Name] = i
 	}
-	if args.FailedSkips > 0 && skipRate > float64(args.FailedSkips) {
-		return errors.New(
-			"skip rate exceeded the failure threshold: " +
-				"provided threshold=" + strconv.Itoa(args.FailedSkips) +
-				"%, actual skip rate=" + strconv.FormatFloat(skipRate, 'f', 2, 64) + "%",
-		)
+
+	for _, srcTest := range src.Tests {
+		i, ok := testIndex[srcTest.Name]
+		if !ok {
+			dst.Tests = append(dst.Tests, srcTest)
+			testIndex[srcTest.Name] = len(dst.Tests) - 1
+			continue
+		}
+		if statusPrecedence(srcTest.Status) > statusPrecedence(dst.Tests[i].Status) {
+			dst.Tests[i] = srcTest
+		}
 	}
-	return nil
 }
 
-// checkUnstableThresholds logs warnings for unstable thresholds for failures and skips.
-func checkUnstableThresholds(suite Suite, args Args) {
-	if args.UnstableFails > 0 && suite.Failures > args.UnstableFails {
-		logrus.Warnf(
-			"Number of failed tests exceeded unstable threshold: "+
-				"provided threshold=%d, actual failed=%d; marking build as UNSTABLE",
-			args.UnstableFails, suite.Failures,
-		)
-	}
-	if args.UnstableSkips > 0 && suite.Skipped > args.UnstableSkips {
-		logrus.Warnf(
-			"Number of skipped tests exceeded unstable threshold: "+
-				"provided threshold=%d, actual skipped=%d; marking build as UNSTABLE",
-			args.UnstableSkips, suite.Skipped,
-		)
+// statusPrecedence ranks TestNG statuses for deduplication: FAIL overrides PASS, and
+// both override SKIP, matching how Jenkins' JUnit publisher reconciles duplicate
+// testcases reported across multiple files.
+func statusPrecedence(status string) int {
+	switch status {
+	case "FAIL":
+		return 3
+	case "PASS":
+		return 2
+	case "SKIP":
+		return 1
+	default:
+		return 0
 	}
 }
 
+// recalculateSuiteTotals recomputes a merged suite's tests/failures/skipped counters
+// from its deduplicated testcases, ignoring configuration methods.
+func recalculateSuiteTotals(suite *Suite) {
+	var tests, failures, skipped int
+	for _, class := range suite.Classes {
+		for _, test := range class.Tests {
+			if test.IsConfig {
+				continue
+			}
+			tests++
+			switch test.Status {
+			case "FAIL":
+				failures++
+			case "SKIP":
+				skipped++
+			}
+		}
+	}
+	suite.Tests = tests
+	suite.Failures = failures
+	suite.Skipped = skipped
+}
+
 // validateConfigFailures checks for failed configuration methods and returns an error if any exist.
 func validateConfigFailures(suite Suite, args Args) error {
 	if args.FailureOnFailedTestConfig {
@@ -212,45 +357,113 @@ func validateConfigFailures(suite Suite, args Args) error {
 }
 
 // convertToJUnit transforms a TestNG report into a JUnit report.
-func convertToJUnit(testNG TestNGReport) (JUnitReport, error) {
+func convertToJUnit(testNG TestNGReport, args Args) (JUnitReport, error) {
 	var junit JUnitReport
 
-	for _, suite := range testNG.Suites {
+	hostname, _ := os.Hostname()
+
+	for suiteIdx, suite := range testNG.Suites {
 		duration, _ := strconv.ParseFloat(suite.Duration, 64)
 		durationSec := duration / 1000
 
 		junitSuite := JUnitSuite{
-			Name:     suite.Name,
-			Tests:    suite.Tests,
-			Failures: suite.Failures,
-			Skipped:  suite.Skipped,
-			Time:     strconv.FormatFloat(durationSec, 'f', 3, 64),
+			Name:      suite.Name,
+			Tests:     suite.Tests,
+			Skipped:   suite.Skipped,
+			Time:      strconv.FormatFloat(durationSec, 'f', 3, 64),
+			Timestamp: suite.StartedAt,
+			Hostname:  hostname,
+			ID:        suiteIdx,
+		}
+
+		if properties := collectProperties(suite, args); len(properties) > 0 {
+			junitSuite.Properties = &JUnitProperties{Properties: properties}
 		}
 
+		var reporterLines, stackTraces []string
 		for _, class := range suite.Classes {
 			for _, test := range class.Tests {
+				reporterLines = append(reporterLines, test.ReporterOutput...)
+
+				// Configuration methods (@BeforeMethod, @AfterClass, etc.) aren't
+				// testcases in their own right; only their reporter-output
+				// contributes to the suite, matching streamClass.
+				if test.IsConfig {
+					continue
+				}
+
 				junitCase := JUnitCase{
 					Name:      test.Name,
 					ClassName: class.Name,
 					Duration:  test.Duration,
 				}
 
-				if test.Status == "FAIL" {
-					junitCase.Failure = &Failure{
-						Message:    test.Exception,
-						Type:       "Failure",
-						StackTrace: test.StackTrace,
+				switch test.Status {
+				case "FAIL":
+					exceptionClass := test.exceptionClass()
+					if exceptionClass != "" && exceptionClass != assertionErrorType {
+						junitCase.Error = &Error{
+							Message:    test.exceptionMessage(),
+							Type:       exceptionClass,
+							StackTrace: test.exceptionStackTrace(),
+						}
+						junitSuite.Errors++
+					} else {
+						junitCase.Failure = &Failure{
+							Message:    test.exceptionMessage(),
+							Type:       "Failure",
+							StackTrace: test.exceptionStackTrace(),
+						}
+						junitSuite.Failures++
 					}
-				} else if test.Status == "SKIP" {
+					if stackTrace := test.exceptionStackTrace(); stackTrace != "" {
+						stackTraces = append(stackTraces, stackTrace)
+					}
+				case "SKIP":
 					junitCase.Skipped = &Skipped{}
 				}
 
 				junitSuite.Cases = append(junitSuite.Cases, junitCase)
 			}
 		}
+		junitSuite.SystemOut = strings.Join(reporterLines, "\n")
+		junitSuite.SystemErr = strings.Join(stackTraces, "\n")
 
 		junit.Suites = append(junit.Suites, junitSuite)
 	}
 
 	return junit, nil
 }
+
+// collectProperties builds a suite's JUnit <properties> block from the TestNG test
+// parameters it contains, optionally extended with the plugin's environment
+// variables. Names are deduplicated, first occurrence wins.
+func collectProperties(suite Suite, args Args) []JUnitProperty {
+	seen := make(map[string]bool)
+	var properties []JUnitProperty
+
+	for _, class := range suite.Classes {
+		for _, test := range class.Tests {
+			for _, param := range test.Parameters {
+				if seen[param.Name] {
+					continue
+				}
+				seen[param.Name] = true
+				properties = append(properties, JUnitProperty{Name: param.Name, Value: param.Value})
+			}
+		}
+	}
+
+	if args.IncludeEnvProperties {
+		for _, env := range os.Environ() {
+			name, value, ok := strings.Cut(env, "=")
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			properties = append(properties, JUnitProperty{Name: name, Value: value})
+		}
+	}
+
+	return properties
+}
@@ -0,0 +1,133 @@
+package plugin
+
+import "testing"
+
+func TestConvertToJUnitErrorVsFailure(t *testing.T) {
+	testNG := TestNGReport{
+		Suites: []Suite{
+			{
+				Name: "Suite1",
+				Classes: []Class{
+					{
+						Name: "com.example.Tests",
+						Tests: []Test{
+							{
+								Name:   "testAssertionFailure",
+								Status: "FAIL",
+								Exception: &TestException{
+									Class:   assertionErrorType,
+									Message: "expected true but found false",
+								},
+							},
+							{
+								Name:   "testUnexpectedException",
+								Status: "FAIL",
+								Exception: &TestException{
+									Class:   "java.lang.NullPointerException",
+									Message: "boom",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	junit, err := convertToJUnit(testNG, Args{})
+	if err != nil {
+		t.Fatalf("convertToJUnit returned error: %v", err)
+	}
+
+	suite := junit.Suites[0]
+	if suite.Failures != 1 || suite.Errors != 1 {
+		t.Fatalf("suite.Failures=%d suite.Errors=%d, want 1/1", suite.Failures, suite.Errors)
+	}
+
+	var assertionCase, exceptionCase *JUnitCase
+	for i, c := range suite.Cases {
+		switch c.Name {
+		case "testAssertionFailure":
+			assertionCase = &suite.Cases[i]
+		case "testUnexpectedException":
+			exceptionCase = &suite.Cases[i]
+		}
+	}
+
+	if assertionCase == nil || assertionCase.Failure == nil || assertionCase.Error != nil {
+		t.Errorf("assertion-error test = %+v, want a <failure> and no <error>", assertionCase)
+	}
+	if exceptionCase == nil || exceptionCase.Error == nil || exceptionCase.Failure != nil {
+		t.Errorf("non-assertion exception test = %+v, want an <error> and no <failure>", exceptionCase)
+	}
+	if exceptionCase.Error.Type != "java.lang.NullPointerException" {
+		t.Errorf("exceptionCase.Error.Type = %q, want java.lang.NullPointerException", exceptionCase.Error.Type)
+	}
+}
+
+func TestCollectProperties(t *testing.T) {
+	suite := Suite{
+		Classes: []Class{
+			{
+				Name: "com.example.Tests",
+				Tests: []Test{
+					{
+						Name:       "testFoo",
+						Parameters: []Param{{Name: "env", Value: "staging"}},
+					},
+					{
+						Name:       "testBar",
+						Parameters: []Param{{Name: "env", Value: "production"}, {Name: "region", Value: "us-east"}},
+					},
+				},
+			},
+		},
+	}
+
+	properties := collectProperties(suite, Args{})
+	want := map[string]string{"env": "staging", "region": "us-east"}
+	if len(properties) != len(want) {
+		t.Fatalf("collectProperties = %+v, want %d entries (first occurrence wins)", properties, len(want))
+	}
+	for _, p := range properties {
+		if want[p.Name] != p.Value {
+			t.Errorf("property %q = %q, want %q", p.Name, p.Value, want[p.Name])
+		}
+	}
+}
+
+func TestCollectPropertiesIncludeEnvProperties(t *testing.T) {
+	t.Setenv("PLUGIN_TEST_ENV_PROPERTY", "env-value")
+
+	suite := Suite{
+		Classes: []Class{
+			{
+				Name: "com.example.Tests",
+				Tests: []Test{
+					{Name: "testFoo", Parameters: []Param{{Name: "PLUGIN_TEST_ENV_PROPERTY", Value: "param-value"}}},
+				},
+			},
+		},
+	}
+
+	properties := collectProperties(suite, Args{IncludeEnvProperties: true})
+
+	var found bool
+	for _, p := range properties {
+		if p.Name != "PLUGIN_TEST_ENV_PROPERTY" {
+			continue
+		}
+		found = true
+		if p.Value != "param-value" {
+			t.Errorf("property PLUGIN_TEST_ENV_PROPERTY = %q, want the test parameter's value (first occurrence wins over env)", p.Value)
+		}
+	}
+	if !found {
+		t.Fatal("expected PLUGIN_TEST_ENV_PROPERTY to be present from the test parameter")
+	}
+
+	withoutEnv := collectProperties(suite, Args{IncludeEnvProperties: false})
+	if len(withoutEnv) != 1 {
+		t.Errorf("without IncludeEnvProperties, collectProperties = %+v, want only the test parameter", withoutEnv)
+	}
+}
@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveIndividualOutput(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          Args
+		filename      string
+		multipleFiles bool
+		wantPath      string
+		wantDiscard   bool
+		wantErr       bool
+	}{
+		{
+			name:     "JUnitOutFile with a single match",
+			args:     Args{JUnitOutFile: "out.xml"},
+			filename: "report.xml",
+			wantPath: "out.xml",
+		},
+		{
+			name:          "JUnitOutFile with multiple matches is rejected",
+			args:          Args{JUnitOutFile: "out.xml"},
+			filename:      "report.xml",
+			multipleFiles: true,
+			wantErr:       true,
+		},
+		{
+			name:     "JUnitOutDir keeps the original basename",
+			args:     Args{JUnitOutDir: "outdir"},
+			filename: filepath.Join("nested", "report.xml"),
+			wantPath: filepath.Join("outdir", "report.xml"),
+		},
+		{
+			name:     "InPlace rewrites the original file",
+			args:     Args{InPlace: true},
+			filename: "report.xml",
+			wantPath: "report.xml",
+		},
+		{
+			name:        "nothing configured discards the conversion",
+			args:        Args{},
+			filename:    "report.xml",
+			wantDiscard: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotPath, gotDiscard, err := resolveIndividualOutput(tc.filename, tc.args, tc.multipleFiles)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotDiscard != tc.wantDiscard {
+				t.Errorf("discard = %v, want %v", gotDiscard, tc.wantDiscard)
+			}
+			if gotPath != tc.wantPath {
+				t.Errorf("outputPath = %q, want %q", gotPath, tc.wantPath)
+			}
+		})
+	}
+}
+
+func TestConvertFileToJUnitOutFile(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "report.xml")
+	out := filepath.Join(dir, "junit.xml")
+	if err := os.WriteFile(in, []byte(sampleTestNGXML), 0644); err != nil {
+		t.Fatalf("failed to write input fixture: %v", err)
+	}
+
+	if err := convertFile(in, Args{JUnitOutFile: out}, false); err != nil {
+		t.Fatalf("convertFile returned error: %v", err)
+	}
+
+	if _, err := os.Stat(in); err != nil {
+		t.Errorf("original report was not left untouched: %v", err)
+	}
+	assertIsJUnitXML(t, out)
+}
+
+func TestConvertFileToJUnitOutDir(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "report.xml")
+	outDir := filepath.Join(dir, "converted")
+	if err := os.WriteFile(in, []byte(sampleTestNGXML), 0644); err != nil {
+		t.Fatalf("failed to write input fixture: %v", err)
+	}
+
+	if err := convertFile(in, Args{JUnitOutDir: outDir}, true); err != nil {
+		t.Fatalf("convertFile returned error: %v", err)
+	}
+
+	assertIsJUnitXML(t, filepath.Join(outDir, "report.xml"))
+}
+
+func TestConvertFileInPlace(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "report.xml")
+	if err := os.WriteFile(in, []byte(sampleTestNGXML), 0644); err != nil {
+		t.Fatalf("failed to write input fixture: %v", err)
+	}
+
+	if err := convertFile(in, Args{InPlace: true}, false); err != nil {
+		t.Fatalf("convertFile returned error: %v", err)
+	}
+
+	assertIsJUnitXML(t, in)
+}
+
+func TestConvertFileDiscard(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "report.xml")
+	if err := os.WriteFile(in, []byte(sampleTestNGXML), 0644); err != nil {
+		t.Fatalf("failed to write input fixture: %v", err)
+	}
+
+	if err := convertFile(in, Args{}, false); err != nil {
+		t.Fatalf("convertFile returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(in)
+	if err != nil {
+		t.Fatalf("failed to read back original file: %v", err)
+	}
+	if string(data) != sampleTestNGXML {
+		t.Error("discarded conversion should leave the original TestNG report untouched")
+	}
+}
+
+func assertIsJUnitXML(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read converted output %s: %v", path, err)
+	}
+	decodeJUnitSuites(t, data)
+}
@@ -0,0 +1,326 @@
+package plugin
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ProcessReader streams a TestNG XML report from r, validating thresholds and
+// encoding the converted JUnit XML to w one suite at a time. Peak memory is bounded
+// to a single suite's testcases rather than the whole document, so this is the entry
+// point to use when embedding the conversion without reading the whole report into
+// memory first.
+func ProcessReader(r io.Reader, w io.Writer, args Args) error {
+	decoder := xml.NewDecoder(r)
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	root := xml.StartElement{Name: xml.Name{Local: "testsuites"}}
+	if err := encoder.EncodeToken(root); err != nil {
+		return err
+	}
+
+	suiteIdx := 0
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.New("failed to parse TestNG XML: " + err.Error())
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "suite" {
+			continue
+		}
+
+		junitSuite, err := streamSuite(decoder, start, suiteIdx, args)
+		if err != nil {
+			return err
+		}
+		suiteIdx++
+		if err := encoder.Encode(junitSuite); err != nil {
+			return errors.New("failed to marshal JUnit XML: " + err.Error())
+		}
+	}
+
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+// convertFile streams filename through ProcessReader and routes the JUnit output to
+// whichever sink resolveIndividualOutput selects for it: a fixed JUnitOutFile, a file
+// of the same name under JUnitOutDir, an in-place rewrite, or discarded entirely.
+func convertFile(filename string, args Args, multipleFiles bool) error {
+	outputPath, discard, err := resolveIndividualOutput(filename, args, multipleFiles)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case discard:
+		return convertFileDiscard(filename, args)
+	case outputPath == filename:
+		return convertFileInPlace(filename, args)
+	default:
+		return convertFileTo(filename, outputPath, args)
+	}
+}
+
+// convertFileDiscard streams filename through the full validate-and-convert pipeline
+// without persisting the JUnit output anywhere, e.g. to check thresholds only.
+func convertFileDiscard(filename string, args Args) error {
+	logrus.Infof("Processing file: %s (no output sink configured; discarding converted JUnit XML)", filename)
+
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return ProcessReader(in, io.Discard, args)
+}
+
+// convertFileTo streams filename's JUnit conversion to outputPath, creating its
+// parent directory if needed (for the JUnitOutDir case).
+func convertFileTo(filename, outputPath string, args Args) error {
+	logrus.Infof("Processing file: %s", filename)
+
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := ProcessReader(in, out, args); err != nil {
+		return err
+	}
+
+	logrus.Infof("Successfully converted %s to JUnit format at %s", filename, outputPath)
+	return nil
+}
+
+// convertFileInPlace streams filename's JUnit conversion through a temp file in the
+// same directory and atomically renames it over the original.
+func convertFileInPlace(filename string, args Args) error {
+	logrus.Infof("Processing file: %s", filename)
+
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if err := ProcessReader(in, tmp, args); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, filename); err != nil {
+		return err
+	}
+
+	logrus.Infof("Successfully converted %s to JUnit format", filename)
+	return nil
+}
+
+// streamSuite decodes one <suite>...</suite> subtree, building its JUnit equivalent
+// and suite counters incrementally as each <test-method> is decoded, then validates
+// thresholds once the suite is fully read.
+func streamSuite(decoder *xml.Decoder, start xml.StartElement, suiteIdx int, args Args) (JUnitSuite, error) {
+	name := attrValue(start, "name")
+	durationMs, _ := strconv.ParseFloat(attrValue(start, "duration-ms"), 64)
+
+	hostname, _ := os.Hostname()
+	junitSuite := JUnitSuite{
+		Name:      name,
+		Time:      strconv.FormatFloat(durationMs/1000, 'f', 3, 64),
+		Timestamp: attrValue(start, "started-at"),
+		Hostname:  hostname,
+		ID:        suiteIdx,
+	}
+
+	var tests, failures, skipped int
+	var reporterLines, stackTraces []string
+	seenProps := make(map[string]bool)
+
+suiteLoop:
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return junitSuite, errors.New("failed to parse TestNG XML: " + err.Error())
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "class" {
+				if err := decoder.Skip(); err != nil {
+					return junitSuite, err
+				}
+				continue
+			}
+			className := attrValue(t, "name")
+			if err := streamClass(decoder, className, &junitSuite, &tests, &failures, &skipped, &reporterLines, &stackTraces, seenProps, args); err != nil {
+				return junitSuite, err
+			}
+		case xml.EndElement:
+			if t.Name.Local == "suite" {
+				break suiteLoop
+			}
+		}
+	}
+
+	if args.IncludeEnvProperties {
+		for _, env := range os.Environ() {
+			name, value, ok := strings.Cut(env, "=")
+			if !ok || seenProps[name] {
+				continue
+			}
+			seenProps[name] = true
+			junitSuite.Properties = appendProperty(junitSuite.Properties, name, value)
+		}
+	}
+
+	junitSuite.Tests = tests
+	junitSuite.Skipped = skipped
+	junitSuite.SystemOut = strings.Join(reporterLines, "\n")
+	junitSuite.SystemErr = strings.Join(stackTraces, "\n")
+
+	if err := validateSuiteThresholds(name, suiteEnvFromCounts(tests, failures, skipped, durationMs), args); err != nil {
+		return junitSuite, err
+	}
+
+	return junitSuite, nil
+}
+
+// streamClass decodes one <class>...</class> subtree, test-method by test-method,
+// updating the enclosing suite's counters and JUnit cases in place.
+func streamClass(
+	decoder *xml.Decoder,
+	className string,
+	junitSuite *JUnitSuite,
+	tests, failures, skipped *int,
+	reporterLines, stackTraces *[]string,
+	seenProps map[string]bool,
+	args Args,
+) error {
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return errors.New("failed to parse TestNG XML: " + err.Error())
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "test-method" {
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			var test Test
+			if err := decoder.DecodeElement(&test, &t); err != nil {
+				return errors.New("failed to parse TestNG XML: " + err.Error())
+			}
+
+			if args.FailureOnFailedTestConfig && test.IsConfig && test.Status == "FAIL" {
+				return errors.New(
+					"a configuration method failed: class=" + className +
+						", method=" + test.Name,
+				)
+			}
+
+			for _, param := range test.Parameters {
+				if seenProps[param.Name] {
+					continue
+				}
+				seenProps[param.Name] = true
+				junitSuite.Properties = appendProperty(junitSuite.Properties, param.Name, param.Value)
+			}
+
+			*reporterLines = append(*reporterLines, test.ReporterOutput...)
+
+			if test.IsConfig {
+				continue
+			}
+			*tests++
+
+			junitCase := JUnitCase{Name: test.Name, ClassName: className, Duration: test.Duration}
+			switch test.Status {
+			case "FAIL":
+				*failures++
+				exceptionClass := test.exceptionClass()
+				if exceptionClass != "" && exceptionClass != assertionErrorType {
+					junitCase.Error = &Error{Message: test.exceptionMessage(), Type: exceptionClass, StackTrace: test.exceptionStackTrace()}
+					junitSuite.Errors++
+				} else {
+					junitCase.Failure = &Failure{Message: test.exceptionMessage(), Type: "Failure", StackTrace: test.exceptionStackTrace()}
+					junitSuite.Failures++
+				}
+				if stackTrace := test.exceptionStackTrace(); stackTrace != "" {
+					*stackTraces = append(*stackTraces, stackTrace)
+				}
+			case "SKIP":
+				*skipped++
+				junitCase.Skipped = &Skipped{}
+			}
+			junitSuite.Cases = append(junitSuite.Cases, junitCase)
+		case xml.EndElement:
+			if t.Name.Local == "class" {
+				return nil
+			}
+		}
+	}
+}
+
+// attrValue returns the value of the named attribute on a start element, or "" if
+// it isn't present.
+func attrValue(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// appendProperty appends a name/value property, allocating the JUnitProperties block
+// on first use.
+func appendProperty(props *JUnitProperties, name, value string) *JUnitProperties {
+	if props == nil {
+		props = &JUnitProperties{}
+	}
+	props.Properties = append(props.Properties, JUnitProperty{Name: name, Value: value})
+	return props
+}
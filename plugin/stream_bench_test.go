@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// buildSyntheticTestNGXML generates a TestNG report with numSuites suites of
+// testsPerSuite test-methods each, for benchmarking the streaming conversion path
+// against inputs much larger than any single suite.
+func buildSyntheticTestNGXML(numSuites, testsPerSuite int) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<testng-results>\n")
+	for s := 0; s < numSuites; s++ {
+		fmt.Fprintf(&b, "  <suite name=\"Suite%d\" duration-ms=\"100\" started-at=\"2026-07-25T10:00:00Z\" tests=\"%d\" failures=\"0\" skipped=\"0\">\n", s, testsPerSuite)
+		b.WriteString("    <class name=\"com.example.Tests\">\n")
+		for t := 0; t < testsPerSuite; t++ {
+			fmt.Fprintf(&b, "      <test-method name=\"test%d\" status=\"PASS\" duration-ms=\"1\">\n", t)
+			b.WriteString("        <reporter-output><line>ok</line></reporter-output>\n")
+			b.WriteString("      </test-method>\n")
+		}
+		b.WriteString("    </class>\n  </suite>\n")
+	}
+	b.WriteString("</testng-results>\n")
+	return b.String()
+}
+
+// BenchmarkProcessReaderManySuites measures streaming conversion throughput and
+// per-op allocations across many small suites. ProcessReader holds at most one
+// suite's testcases in memory at a time, but b.AllocsPerOp counts every allocation
+// made while converting the whole input, so it grows with the total number of
+// test-methods across all suites; peak memory is not measured here.
+func BenchmarkProcessReaderManySuites(b *testing.B) {
+	input := buildSyntheticTestNGXML(500, 20)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ProcessReader(strings.NewReader(input), io.Discard, Args{}); err != nil {
+			b.Fatalf("ProcessReader returned error: %v", err)
+		}
+	}
+}
+
+// BenchmarkProcessReaderOneLargeSuite measures the same workload concentrated into a
+// single large suite, the worst case for streamSuite's per-suite buffering.
+func BenchmarkProcessReaderOneLargeSuite(b *testing.B) {
+	input := buildSyntheticTestNGXML(1, 10000)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ProcessReader(strings.NewReader(input), io.Discard, Args{}); err != nil {
+			b.Fatalf("ProcessReader returned error: %v", err)
+		}
+	}
+}
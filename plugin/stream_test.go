@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const sampleTestNGXML = `<?xml version="1.0" encoding="UTF-8"?>
+<testng-results>
+  <suite name="Suite1" duration-ms="120" started-at="2026-07-25T10:00:00Z" tests="2" failures="1" skipped="0">
+    <class name="com.example.Tests">
+      <test-method name="beforeMethod" is-config="true" status="PASS" duration-ms="1"/>
+      <test-method name="testPass" status="PASS" duration-ms="10">
+        <params>
+          <param name="env"><value>staging</value></param>
+        </params>
+        <reporter-output>
+          <line>hello from testPass</line>
+        </reporter-output>
+      </test-method>
+      <test-method name="testFail" status="FAIL" duration-ms="20">
+        <exception class="java.lang.AssertionError">
+          <message>expected true but found false</message>
+          <full-stacktrace>java.lang.AssertionError: expected true but found false
+	at com.example.Tests.testFail(Tests.java:42)</full-stacktrace>
+        </exception>
+      </test-method>
+    </class>
+  </suite>
+  <suite name="Suite2" duration-ms="50" started-at="2026-07-25T10:01:00Z" tests="1" failures="0" skipped="1">
+    <class name="com.example.More">
+      <test-method name="testSkip" status="SKIP" duration-ms="0"/>
+    </class>
+  </suite>
+</testng-results>
+`
+
+func decodeJUnitSuites(t *testing.T, data []byte) []JUnitSuite {
+	t.Helper()
+	var report JUnitReport
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("failed to unmarshal streamed JUnit XML: %v\n%s", err, data)
+	}
+	return report.Suites
+}
+
+func TestProcessReaderRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	if err := ProcessReader(strings.NewReader(sampleTestNGXML), &out, Args{}); err != nil {
+		t.Fatalf("ProcessReader returned error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "<testsuite ") {
+		t.Errorf("expected streamed output to contain <testsuite> elements, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "<JUnitSuite") {
+		t.Errorf("streamed output leaked the Go type name instead of <testsuite>:\n%s", out.String())
+	}
+
+	suites := decodeJUnitSuites(t, out.Bytes())
+	if len(suites) != 2 {
+		t.Fatalf("expected 2 suites, got %d", len(suites))
+	}
+
+	suite1 := suites[0]
+	if suite1.ID != 0 {
+		t.Errorf("suite1.ID = %d, want 0", suite1.ID)
+	}
+	if suite1.Timestamp != "2026-07-25T10:00:00Z" {
+		t.Errorf("suite1.Timestamp = %q, want the suite's started-at attribute", suite1.Timestamp)
+	}
+	if suite1.Tests != 2 {
+		t.Errorf("suite1.Tests = %d, want 2 (is-config method excluded)", suite1.Tests)
+	}
+	for _, c := range suite1.Cases {
+		if c.Name == "beforeMethod" {
+			t.Error("is-config method leaked into streamed testcases")
+		}
+	}
+	if !strings.Contains(suite1.SystemOut, "hello from testPass") {
+		t.Errorf("suite1.SystemOut = %q, want it to contain the reporter-output line", suite1.SystemOut)
+	}
+	if suite1.Properties == nil || len(suite1.Properties.Properties) != 1 ||
+		suite1.Properties.Properties[0].Name != "env" || suite1.Properties.Properties[0].Value != "staging" {
+		t.Errorf("suite1.Properties = %+v, want a single env=staging property", suite1.Properties)
+	}
+	if !strings.Contains(suite1.SystemErr, "AssertionError") {
+		t.Errorf("suite1.SystemErr = %q, want it to contain the failed test's stack trace", suite1.SystemErr)
+	}
+
+	suite2 := suites[1]
+	if suite2.ID != 1 {
+		t.Errorf("suite2.ID = %d, want 1", suite2.ID)
+	}
+}
+
+func TestProcessReaderMatchesConvertToJUnit(t *testing.T) {
+	var testNG TestNGReport
+	if err := xml.Unmarshal([]byte(sampleTestNGXML), &testNG); err != nil {
+		t.Fatalf("failed to unmarshal sample TestNG XML: %v", err)
+	}
+
+	junit, err := convertToJUnit(testNG, Args{})
+	if err != nil {
+		t.Fatalf("convertToJUnit returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := ProcessReader(strings.NewReader(sampleTestNGXML), &out, Args{}); err != nil {
+		t.Fatalf("ProcessReader returned error: %v", err)
+	}
+	streamed := decodeJUnitSuites(t, out.Bytes())
+
+	if len(junit.Suites) != len(streamed) {
+		t.Fatalf("merge path produced %d suites, streaming path produced %d", len(junit.Suites), len(streamed))
+	}
+
+	for i := range junit.Suites {
+		batch, stream := junit.Suites[i], streamed[i]
+		if batch.Tests != stream.Tests || batch.Failures != stream.Failures || batch.Skipped != stream.Skipped {
+			t.Errorf("suite %d counters differ: merge=%+v streaming tests=%d failures=%d skipped=%d",
+				i, batch, stream.Tests, stream.Failures, stream.Skipped)
+		}
+		if len(batch.Cases) != len(stream.Cases) {
+			t.Errorf("suite %d: merge path has %d cases, streaming path has %d", i, len(batch.Cases), len(stream.Cases))
+		}
+	}
+}
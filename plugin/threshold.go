@@ -0,0 +1,529 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// validateThresholds evaluates each configured threshold expression against every
+// suite in the report, failing the build on the first one that evaluates to false.
+// UnstableThresholds are evaluated the same way but only log a warning.
+func validateThresholds(report TestNGReport, args Args) error {
+	for _, suite := range report.Suites {
+		if err := validateSuiteThresholds(suite.Name, suiteEnv(suite), args); err != nil {
+			return err
+		}
+		if err := validateConfigFailures(suite, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSuiteThresholds evaluates every configured threshold expression against a
+// single suite's identifier environment. It is also used by the streaming conversion
+// path, which builds env incrementally rather than from a fully-parsed Suite.
+func validateSuiteThresholds(suiteName string, env map[string]float64, args Args) error {
+	thresholds := append(append([]string{}, args.Thresholds...), legacyThresholds(args)...)
+	unstableThresholds := append(append([]string{}, args.UnstableThresholds...), legacyUnstableThresholds(args)...)
+
+	for _, expr := range thresholds {
+		ok, err := evaluateExpression(expr, env)
+		if err != nil {
+			return fmt.Errorf("invalid threshold expression %q: %w", expr, err)
+		}
+		if !ok {
+			return fmt.Errorf(
+				"threshold failed for suite %q: %s (tests=%d, failures=%d, skipped=%d, duration_ms=%d)",
+				suiteName, expr, int(env["tests"]), int(env["failures"]), int(env["skipped"]), int(env["duration_ms"]),
+			)
+		}
+	}
+
+	for _, expr := range unstableThresholds {
+		ok, err := evaluateExpression(expr, env)
+		if err != nil {
+			return fmt.Errorf("invalid unstable threshold expression %q: %w", expr, err)
+		}
+		if !ok {
+			logrus.Warnf(
+				"unstable threshold failed for suite %q: %s (tests=%d, failures=%d, skipped=%d); marking build as UNSTABLE",
+				suiteName, expr, int(env["tests"]), int(env["failures"]), int(env["skipped"]),
+			)
+		}
+	}
+
+	return nil
+}
+
+// suiteEnv builds the per-suite identifier environment that threshold expressions
+// are evaluated against.
+func suiteEnv(suite Suite) map[string]float64 {
+	durationMs, _ := strconv.ParseFloat(suite.Duration, 64)
+	return suiteEnvFromCounts(suite.Tests, suite.Failures, suite.Skipped, durationMs)
+}
+
+// suiteEnvFromCounts builds the identifier environment from raw suite counters,
+// letting callers that compute them incrementally (e.g. the streaming parser) avoid
+// building a full Suite just to evaluate thresholds.
+func suiteEnvFromCounts(tests, failures, skipped int, durationMs float64) map[string]float64 {
+	var failureRate, skipRate float64
+	if tests > 0 {
+		failureRate = float64(failures) / float64(tests)
+		skipRate = float64(skipped) / float64(tests)
+	}
+
+	return map[string]float64{
+		"tests":        float64(tests),
+		"failures":     float64(failures),
+		"skipped":      float64(skipped),
+		"passed":       float64(tests - failures - skipped),
+		"duration_ms":  durationMs,
+		"failure_rate": failureRate,
+		"skip_rate":    skipRate,
+	}
+}
+
+// legacyThresholds translates the deprecated absolute/percentage ThresholdMode into
+// the equivalent expression form, so old Args configurations keep working unchanged.
+func legacyThresholds(args Args) []string {
+	var exprs []string
+	switch args.ThresholdMode {
+	case 1:
+		if args.FailedFails > 0 {
+			exprs = append(exprs, fmt.Sprintf("failures <= %d", args.FailedFails))
+		}
+		if args.FailedSkips > 0 {
+			exprs = append(exprs, fmt.Sprintf("skipped <= %d", args.FailedSkips))
+		}
+	case 2:
+		// Guard with "tests == 0 ||" so an empty suite is skipped rather than dividing
+		// by zero, matching the old validatePercentageThresholds behavior.
+		if args.FailedFails > 0 {
+			exprs = append(exprs, fmt.Sprintf("tests == 0 || failures * 100 / tests <= %d", args.FailedFails))
+		}
+		if args.FailedSkips > 0 {
+			exprs = append(exprs, fmt.Sprintf("tests == 0 || skipped * 100 / tests <= %d", args.FailedSkips))
+		}
+	}
+	return exprs
+}
+
+// legacyUnstableThresholds translates the deprecated UnstableFails/UnstableSkips
+// counters into the equivalent expression form. These were always absolute
+// thresholds, regardless of ThresholdMode.
+func legacyUnstableThresholds(args Args) []string {
+	var exprs []string
+	if args.UnstableFails > 0 {
+		exprs = append(exprs, fmt.Sprintf("failures <= %d", args.UnstableFails))
+	}
+	if args.UnstableSkips > 0 {
+		exprs = append(exprs, fmt.Sprintf("skipped <= %d", args.UnstableSkips))
+	}
+	return exprs
+}
+
+// evaluateExpression parses and evaluates a single threshold expression against env,
+// returning whether it holds true.
+func evaluateExpression(expr string, env map[string]float64) (bool, error) {
+	node, err := parseExpression(expr)
+	if err != nil {
+		return false, err
+	}
+	value, err := node.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return value != 0, nil
+}
+
+// exprNode is a node in a threshold expression's AST.
+type exprNode interface {
+	eval(env map[string]float64) (float64, error)
+}
+
+// numberNode is a numeric literal, e.g. 5 or 0.1.
+type numberNode struct {
+	value float64
+}
+
+func (n *numberNode) eval(map[string]float64) (float64, error) {
+	return n.value, nil
+}
+
+// identNode is a reference to an environment identifier, e.g. failures.
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(env map[string]float64) (float64, error) {
+	value, ok := env[n.name]
+	if !ok {
+		return 0, fmt.Errorf("unknown identifier %q", n.name)
+	}
+	return value, nil
+}
+
+// unaryNode is a prefix operator applied to a single operand: -x or !x.
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n *unaryNode) eval(env map[string]float64) (float64, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "-":
+		return -value, nil
+	case "!":
+		return boolToFloat(value == 0), nil
+	default:
+		return 0, fmt.Errorf("unknown unary operator %q", n.op)
+	}
+}
+
+// binaryNode is an infix operator applied to two operands.
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(env map[string]float64) (float64, error) {
+	// && and || short-circuit so that, e.g., "tests == 0 || failures/tests < 0.1"
+	// doesn't divide by zero when the suite has no tests.
+	if n.op == "&&" || n.op == "||" {
+		left, err := n.left.eval(env)
+		if err != nil {
+			return 0, err
+		}
+		if n.op == "&&" && left == 0 {
+			return 0, nil
+		}
+		if n.op == "||" && left != 0 {
+			return 1, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return 0, err
+		}
+		return boolToFloat(right != 0), nil
+	}
+
+	left, err := n.left.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	case "/":
+		if right == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return left / right, nil
+	case "<":
+		return boolToFloat(left < right), nil
+	case "<=":
+		return boolToFloat(left <= right), nil
+	case ">":
+		return boolToFloat(left > right), nil
+	case ">=":
+		return boolToFloat(left >= right), nil
+	case "==":
+		return boolToFloat(left == right), nil
+	case "!=":
+		return boolToFloat(left != right), nil
+	default:
+		return 0, fmt.Errorf("unknown binary operator %q", n.op)
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// tokenKind identifies the kind of a lexed token.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lexExpression tokenizes a threshold expression.
+func lexExpression(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")"})
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(runes) && (runes[i] >= '0' && runes[i] <= '9' || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i])})
+		case isIdentStart(c):
+			start := i
+			for i < len(runes) && isIdentPart(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i])})
+		case c == '&' && i+1 < len(runes) && runes[i+1] == '&':
+			tokens = append(tokens, token{kind: tokenOp, text: "&&"})
+			i += 2
+		case c == '|' && i+1 < len(runes) && runes[i+1] == '|':
+			tokens = append(tokens, token{kind: tokenOp, text: "||"})
+			i += 2
+		case c == '<' || c == '>' || c == '=' || c == '!':
+			op := string(c)
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				op += "="
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op})
+		case c == '+' || c == '-' || c == '*' || c == '/':
+			tokens = append(tokens, token{kind: tokenOp, text: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF})
+	return tokens, nil
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// exprParser is a recursive-descent parser over a token stream, implementing the
+// following grammar (lowest to highest precedence):
+//
+//	expr       := orExpr
+//	orExpr     := andExpr ( "||" andExpr )*
+//	andExpr    := notExpr ( "&&" notExpr )*
+//	notExpr    := "!" notExpr | comparison
+//	comparison := additive ( ("<"|"<="|">"|">="|"=="|"!=") additive )?
+//	additive   := multiplicative ( ("+"|"-") multiplicative )*
+//	multiplicative := unary ( ("*"|"/") unary )*
+//	unary      := "-" unary | primary
+//	primary    := number | ident | "(" expr ")"
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpression parses a single threshold expression into an AST.
+func parseExpression(expr string) (exprNode, error) {
+	tokens, err := lexExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected token %q", p.peek().text)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) advance() token {
+	t := p.tokens[p.pos]
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && p.peek().text == "||" {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && p.peek().text == "&&" {
+		p.advance()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.peek().kind == tokenOp && p.peek().text == "!" {
+		p.advance()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "!", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokenOp && isComparisonOp(p.peek().text) {
+		op := p.advance().text
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func isComparisonOp(op string) bool {
+	switch op {
+	case "<", "<=", ">", ">=", "==", "!=":
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && (p.peek().text == "+" || p.peek().text == "-") {
+		op := p.advance().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && (p.peek().text == "*" || p.peek().text == "/") {
+		op := p.advance().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek().kind == tokenOp && p.peek().text == "-" {
+		p.advance()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber:
+		p.advance()
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return &numberNode{value: value}, nil
+	case tokenIdent:
+		p.advance()
+		return &identNode{name: t.text}, nil
+	case tokenLParen:
+		p.advance()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenRParen {
+			return nil, fmt.Errorf("expected closing parenthesis")
+		}
+		p.advance()
+		return node, nil
+	default:
+		return nil, fmt.Errorf("unexpected token %q", t.text)
+	}
+}
@@ -0,0 +1,117 @@
+package plugin
+
+import "testing"
+
+func TestEvaluateExpression(t *testing.T) {
+	env := map[string]float64{
+		"tests":        10,
+		"failures":     2,
+		"skipped":      1,
+		"passed":       7,
+		"duration_ms":  1500,
+		"failure_rate": 0.2,
+		"skip_rate":    0.1,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"failures < 5", true},
+		{"failures >= 5", false},
+		{"failures * 100 / tests <= 20", true},
+		{"failures * 100 / tests <= 10", false},
+		{"tests == 0 || failure_rate < 0.1", false},
+		{"tests == 0 || failure_rate < 0.5", true},
+		{"!(failures > 5)", true},
+		{"failures > 0 && skipped > 0", true},
+		{"failures > 0 && skipped > 5", false},
+		{"(failures + skipped) < tests", true},
+		{"-failures < 0", true},
+	}
+
+	for _, tc := range tests {
+		got, err := evaluateExpression(tc.expr, env)
+		if err != nil {
+			t.Fatalf("evaluateExpression(%q) returned error: %v", tc.expr, err)
+		}
+		if got != tc.want {
+			t.Errorf("evaluateExpression(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestEvaluateExpressionDivisionByZero(t *testing.T) {
+	env := map[string]float64{"tests": 0, "failures": 0}
+
+	if _, err := evaluateExpression("failures/tests < 0.1", env); err == nil {
+		t.Fatal("expected division-by-zero error, got nil")
+	}
+
+	// The guarded form short-circuits before the division happens.
+	ok, err := evaluateExpression("tests == 0 || failures/tests < 0.1", env)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected guarded expression to short-circuit to true")
+	}
+}
+
+func TestEvaluateExpressionUnknownIdentifier(t *testing.T) {
+	if _, err := evaluateExpression("bogus < 5", map[string]float64{"tests": 1}); err == nil {
+		t.Fatal("expected error for unknown identifier, got nil")
+	}
+}
+
+func TestParseExpressionSyntaxError(t *testing.T) {
+	if _, err := parseExpression("failures <"); err == nil {
+		t.Fatal("expected parse error for incomplete expression, got nil")
+	}
+	if _, err := parseExpression("(failures < 5"); err == nil {
+		t.Fatal("expected parse error for unclosed parenthesis, got nil")
+	}
+}
+
+func TestLegacyThresholds(t *testing.T) {
+	absolute := Args{ThresholdMode: 1, FailedFails: 5, FailedSkips: 2}
+	got := legacyThresholds(absolute)
+	want := []string{"failures <= 5", "skipped <= 2"}
+	if len(got) != len(want) {
+		t.Fatalf("legacyThresholds(absolute) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("legacyThresholds(absolute)[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	percentage := Args{ThresholdMode: 2, FailedFails: 10}
+	got = legacyThresholds(percentage)
+	if len(got) != 1 || got[0] != "tests == 0 || failures * 100 / tests <= 10" {
+		t.Errorf("legacyThresholds(percentage) = %v", got)
+	}
+
+	none := Args{}
+	if got := legacyThresholds(none); got != nil {
+		t.Errorf("legacyThresholds(none) = %v, want nil", got)
+	}
+}
+
+func TestSuiteEnvFromCounts(t *testing.T) {
+	env := suiteEnvFromCounts(10, 2, 3, 500)
+	if env["passed"] != 5 {
+		t.Errorf("passed = %v, want 5", env["passed"])
+	}
+	if env["failure_rate"] != 0.2 {
+		t.Errorf("failure_rate = %v, want 0.2", env["failure_rate"])
+	}
+	if env["skip_rate"] != 0.3 {
+		t.Errorf("skip_rate = %v, want 0.3", env["skip_rate"])
+	}
+
+	empty := suiteEnvFromCounts(0, 0, 0, 0)
+	if empty["failure_rate"] != 0 || empty["skip_rate"] != 0 {
+		t.Errorf("empty suite env = %+v, want zeroed rates", empty)
+	}
+}
@@ -10,12 +10,13 @@ type TestNGReport struct {
 
 // Suite represents a TestNG suite.
 type Suite struct {
-	Name     string  `xml:"name,attr"`
-	Duration string  `xml:"duration-ms,attr"`
-	Tests    int     `xml:"tests,attr"`
-	Failures int     `xml:"failures,attr"`
-	Skipped  int     `xml:"skipped,attr"`
-	Classes  []Class `xml:"class"`
+	Name      string  `xml:"name,attr"`
+	Duration  string  `xml:"duration-ms,attr"`
+	StartedAt string  `xml:"started-at,attr"`
+	Tests     int     `xml:"tests,attr"`
+	Failures  int     `xml:"failures,attr"`
+	Skipped   int     `xml:"skipped,attr"`
+	Classes   []Class `xml:"class"`
 }
 
 // Class represents a TestNG class.
@@ -26,20 +27,59 @@ type Class struct {
 
 // Test represents a TestNG test or configuration method.
 type Test struct {
-	Name       string  `xml:"name,attr"`
-	ClassName  string  `xml:"class,attr"`
-	Status     string  `xml:"status,attr"`
-	Duration   string  `xml:"duration-ms,attr"`
-	IsConfig   bool    `xml:"is-config,attr"`
-	Parameters []Param `xml:"params>param"`
-	Exception  string  `xml:"exception>message"`
-	StackTrace string  `xml:"exception>full-stacktrace"`
+	Name           string         `xml:"name,attr"`
+	ClassName      string         `xml:"class,attr"`
+	Status         string         `xml:"status,attr"`
+	Duration       string         `xml:"duration-ms,attr"`
+	IsConfig       bool           `xml:"is-config,attr"`
+	StartedAt      string         `xml:"started-at,attr"`
+	Parameters     []Param        `xml:"params>param"`
+	Exception      *TestException `xml:"exception"`
+	ReporterOutput []string       `xml:"reporter-output>line"`
 }
 
-// Param represents a parameter passed to a test method.
+// TestException represents a TestNG test-method's <exception> element. encoding/xml
+// rejects combining the attr flag with a ">"-chained element path, so the exception
+// class attribute has to be read off a nested struct rather than a flattened field
+// on Test.
+type TestException struct {
+	// Class is TestNG's exception class attribute, e.g. "java.lang.AssertionError"
+	// for a failed assertion versus some other throwable. It distinguishes JUnit
+	// <failure> from <error> in convertToJUnit.
+	Class      string `xml:"class,attr"`
+	Message    string `xml:"message"`
+	StackTrace string `xml:"full-stacktrace"`
+}
+
+// exceptionClass returns the test's exception class, or "" if it didn't fail with one.
+func (t Test) exceptionClass() string {
+	if t.Exception == nil {
+		return ""
+	}
+	return t.Exception.Class
+}
+
+// exceptionMessage returns the test's exception message, or "" if it didn't fail with one.
+func (t Test) exceptionMessage() string {
+	if t.Exception == nil {
+		return ""
+	}
+	return t.Exception.Message
+}
+
+// exceptionStackTrace returns the test's exception stack trace, or "" if it didn't fail with one.
+func (t Test) exceptionStackTrace() string {
+	if t.Exception == nil {
+		return ""
+	}
+	return t.Exception.StackTrace
+}
+
+// Param represents a parameter passed to a test method. TestNG nests the value in a
+// child <value> element rather than as direct chardata on <param>.
 type Param struct {
 	Name  string `xml:"name,attr"`
-	Value string `xml:",chardata"`
+	Value string `xml:"value"`
 }
 
 // JUnitReport represents the structure of a JUnit XML report.
@@ -48,14 +88,36 @@ type JUnitReport struct {
 	Suites  []JUnitSuite `xml:"testsuite"`
 }
 
-// JUnitSuite represents a JUnit test suite.
+// JUnitSuite represents a JUnit test suite. XMLName is set explicitly so that
+// encoding a JUnitSuite on its own, as the streaming path does one suite at a time,
+// emits <testsuite> instead of falling back to the Go type name.
 type JUnitSuite struct {
-	Name     string      `xml:"name,attr"`
-	Tests    int         `xml:"tests,attr"`
-	Failures int         `xml:"failures,attr"`
-	Skipped  int         `xml:"skipped,attr"`
-	Time     string      `xml:"time,attr"`
-	Cases    []JUnitCase `xml:"testcase"`
+	XMLName    xml.Name         `xml:"testsuite"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Hostname   string           `xml:"hostname,attr,omitempty"`
+	ID         int              `xml:"id,attr"`
+	Properties *JUnitProperties `xml:"properties,omitempty"`
+	Cases      []JUnitCase      `xml:"testcase"`
+	SystemOut  string           `xml:"system-out,omitempty"`
+	SystemErr  string           `xml:"system-err,omitempty"`
+}
+
+// JUnitProperties wraps the <properties> block of a JUnit suite.
+type JUnitProperties struct {
+	Properties []JUnitProperty `xml:"property"`
+}
+
+// JUnitProperty represents a single name/value property, sourced from TestNG
+// test parameters or, when enabled, the plugin's environment variables.
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
 }
 
 // JUnitCase represents a JUnit test case.
@@ -64,15 +126,24 @@ type JUnitCase struct {
 	ClassName string   `xml:"classname,attr"`
 	Duration  string   `xml:"time,attr"`
 	Failure   *Failure `xml:"failure,omitempty"`
+	Error     *Error   `xml:"error,omitempty"`
 	Skipped   *Skipped `xml:"skipped,omitempty"`
 }
 
-// Failure represents a failed test case.
+// Failure represents a test case that failed an assertion.
 type Failure struct {
 	Message    string `xml:"message,attr"`
 	Type       string `xml:"type,attr"`
 	StackTrace string `xml:",chardata"`
 }
 
+// Error represents a test case that failed with a throwable other than an
+// assertion error, e.g. an unexpected exception raised by the test itself.
+type Error struct {
+	Message    string `xml:"message,attr"`
+	Type       string `xml:"type,attr"`
+	StackTrace string `xml:",chardata"`
+}
+
 // Skipped represents a skipped test case.
 type Skipped struct{}